@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/Sirupsen/logrus"
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+
+	"github.com/cardigann/cardigann/config"
+)
+
+// Options carries logging settings that come from command line flags
+// rather than the config file. A non-empty field here always wins over
+// the equivalent [logging] config value, so operators can override the
+// config on the fly with e.g. --log-level.
+type Options struct {
+	Level  string
+	Format string
+	File   string
+}
+
+// Configure points Logger at the output, level and format described by
+// the [logging] section of cfg, with opts taking precedence over
+// whatever the config says.
+func Configure(cfg config.Config, opts Options) error {
+	level := opts.Level
+	if level == "" {
+		var err error
+		level, err = config.Get("logging", "level", "info", cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	parsedLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("Invalid log level %q: %s", level, err.Error())
+	}
+	SetLevel(parsedLevel)
+
+	format := opts.Format
+	if format == "" {
+		format, err = config.Get("logging", "format", "text", cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case "json":
+		SetFormatter(&logrus.JSONFormatter{})
+	case "text":
+		SetFormatter(&logrus.TextFormatter{})
+	default:
+		return fmt.Errorf("Invalid log format %q, must be text or json", format)
+	}
+
+	file := opts.File
+	if file == "" {
+		file, err = config.Get("logging", "file", "", cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	stdout, err := config.GetBool("logging", "stdout", file == "", cfg)
+	if err != nil {
+		return err
+	}
+
+	writers := []io.Writer{}
+	if stdout {
+		writers = append(writers, os.Stdout)
+	}
+
+	if file != "" {
+		maxSize, err := config.GetInt("logging", "max_size_mb", 100, cfg)
+		if err != nil {
+			return err
+		}
+		maxBackups, err := config.GetInt("logging", "max_backups", 3, cfg)
+		if err != nil {
+			return err
+		}
+		maxAge, err := config.GetInt("logging", "max_age_days", 28, cfg)
+		if err != nil {
+			return err
+		}
+		compress, err := config.GetBool("logging", "compress", false, cfg)
+		if err != nil {
+			return err
+		}
+
+		writers = append(writers, &lumberjack.Logger{
+			Filename:   file,
+			MaxSize:    maxSize,
+			MaxBackups: maxBackups,
+			MaxAge:     maxAge,
+			Compress:   compress,
+		})
+	}
+
+	syslogAddr, err := config.Get("logging", "syslog", "", cfg)
+	if err != nil {
+		return err
+	}
+	if syslogAddr != "" {
+		hook, err := newSyslogHook(syslogAddr)
+		if err != nil {
+			return fmt.Errorf("Configuring syslog failed: %s", err.Error())
+		}
+		AddHook(hook)
+	}
+
+	switch len(writers) {
+	case 0:
+		SetOutput(ioutil.Discard)
+	case 1:
+		SetOutput(writers[0])
+	default:
+		SetOutput(io.MultiWriter(writers...))
+	}
+
+	return nil
+}
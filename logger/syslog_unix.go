@@ -0,0 +1,16 @@
+// +build !windows
+
+package logger
+
+import (
+	"log/syslog"
+
+	"github.com/Sirupsen/logrus"
+	logrus_syslog "github.com/Sirupsen/logrus/hooks/syslog"
+)
+
+// newSyslogHook dials addr (host:port) over UDP and ships log entries to
+// it via the standard syslog protocol.
+func newSyslogHook(addr string) (logrus.Hook, error) {
+	return logrus_syslog.NewSyslogHook("udp", addr, syslog.LOG_INFO, "cardigann")
+}
@@ -0,0 +1,14 @@
+// +build windows
+
+package logger
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// newSyslogHook always fails on Windows, which has no syslog daemon.
+func newSyslogHook(addr string) (logrus.Hook, error) {
+	return nil, fmt.Errorf("syslog logging is not supported on windows")
+}
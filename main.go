@@ -6,7 +6,6 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
@@ -21,8 +20,10 @@ import (
 	"github.com/cardigann/cardigann/logger"
 	"github.com/cardigann/cardigann/server"
 	"github.com/cardigann/cardigann/torznab"
+	"github.com/cheggaaa/pb"
 	"github.com/equinox-io/equinox"
 	"github.com/kardianos/service"
+	"golang.org/x/term"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -74,7 +75,25 @@ func newConfig() (config.Config, error) {
 	}
 
 	log.WithField("path", f).Debug("Reading config")
-	return config.NewJSONConfig(f)
+	conf, err := config.NewJSONConfig(f)
+	if err != nil {
+		return nil, err
+	}
+
+	level := globals.LogLevel
+	if globals.Debug {
+		level = "debug"
+	}
+
+	if err := logger.Configure(conf, logger.Options{
+		Level:  level,
+		Format: globals.LogFormat,
+		File:   globals.LogFile,
+	}); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
 }
 
 func lookupRunner(key string, opts indexer.RunnerOpts) (torznab.Indexer, error) {
@@ -87,7 +106,8 @@ func lookupRunner(key string, opts indexer.RunnerOpts) (torznab.Indexer, error)
 		return nil, err
 	}
 
-	return indexer.NewRunner(def, opts), nil
+	log.WithField("indexer", key).Debug("Creating indexer runner")
+	return torznab.Instrument(indexer.NewRunner(def, opts), key), nil
 }
 
 func lookupAggregate(opts indexer.RunnerOpts) (torznab.Indexer, error) {
@@ -104,7 +124,8 @@ func lookupAggregate(opts indexer.RunnerOpts) (torznab.Indexer, error) {
 				return nil, err
 			}
 
-			agg = append(agg, indexer.NewRunner(def, opts))
+			log.WithField("indexer", key).Debug("Creating indexer runner")
+			agg = append(agg, torznab.Instrument(indexer.NewRunner(def, opts), key))
 		}
 	}
 
@@ -112,11 +133,23 @@ func lookupAggregate(opts indexer.RunnerOpts) (torznab.Indexer, error) {
 }
 
 var globals struct {
-	Debug bool
+	Debug     bool
+	LogLevel  string
+	LogFormat string
+	LogFile   string
 }
 
 func configureGlobalFlags(cmd *kingpin.CmdClause) {
 	cmd.Flag("debug", "Print out debug logging").BoolVar(&globals.Debug)
+
+	cmd.Flag("log-level", "Override the configured log level").
+		StringVar(&globals.LogLevel)
+
+	cmd.Flag("log-format", "Override the configured log format (text or json)").
+		EnumVar(&globals.LogFormat, "text", "json")
+
+	cmd.Flag("log-file", "Override the configured log file path").
+		StringVar(&globals.LogFile)
 }
 
 func applyGlobalFlags() {
@@ -131,10 +164,10 @@ func configureQueryCommand(app *kingpin.Application) {
 
 	cmd := app.Command("query", "Manually query an indexer using torznab commands")
 	cmd.Alias("q")
-	cmd.Flag("format", "Either json, xml or rss").
+	cmd.Flag("format", "Either json, xml, rss or atom").
 		Default("json").
 		Short('f').
-		EnumVar(&format, "xml", "json", "rss")
+		EnumVar(&format, "xml", "json", "rss", "atom")
 
 	cmd.Arg("key", "The indexer key").
 		Required().
@@ -198,6 +231,13 @@ func queryCommand(key, format string, args []string) error {
 			return fmt.Errorf("Failed to marshal JSON: %s", err.Error())
 		}
 		fmt.Printf("%s", j)
+
+	case "atom":
+		a, err := torznab.MarshalAtom(&feed)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal Atom: %s", err.Error())
+		}
+		fmt.Printf("%s", a)
 	}
 
 	return nil
@@ -205,6 +245,7 @@ func queryCommand(key, format string, args []string) error {
 
 func configureDownloadCommand(app *kingpin.Application) {
 	var key, url, file string
+	var quiet bool
 
 	cmd := app.Command("download", "Download a torrent from the tracker")
 	cmd.Arg("key", "The indexer key").
@@ -215,19 +256,23 @@ func configureDownloadCommand(app *kingpin.Application) {
 		Required().
 		StringVar(&url)
 
-	cmd.Arg("file", "The filename to download to").
+	cmd.Arg("file", "The filename to download to, or - for stdout").
 		Required().
 		StringVar(&file)
 
+	cmd.Flag("quiet", "Don't show a progress bar").
+		Short('q').
+		BoolVar(&quiet)
+
 	configureGlobalFlags(cmd)
 
 	cmd.Action(func(c *kingpin.ParseContext) error {
 		applyGlobalFlags()
-		return downloadCommand(key, url, file)
+		return downloadCommand(key, url, file, quiet)
 	})
 }
 
-func downloadCommand(key, url, file string) error {
+func downloadCommand(key, url, file string, quiet bool) error {
 	conf, err := newConfig()
 	if err != nil {
 		return err
@@ -240,38 +285,62 @@ func downloadCommand(key, url, file string) error {
 		return err
 	}
 
-	rc, _, err := indexer.Download(url)
+	rc, size, err := indexer.Download(url)
 	if err != nil {
 		return fmt.Errorf("Downloading failed: %s", err.Error())
 	}
 
 	defer rc.Close()
 
-	f, err := os.Create(file)
-	if err != nil {
-		return fmt.Errorf("Creating file failed: %s", err.Error())
+	var w io.Writer
+	toStdout := file == "-"
+
+	if toStdout {
+		w = os.Stdout
+	} else {
+		f, err := os.Create(file)
+		if err != nil {
+			return fmt.Errorf("Creating file failed: %s", err.Error())
+		}
+		defer f.Close()
+		w = f
 	}
 
-	n, err := io.Copy(f, rc)
-	if err != nil {
-		return fmt.Errorf("Creating file failed: %s", err.Error())
+	showBar := !quiet && term.IsTerminal(int(os.Stderr.Fd()))
+	if toStdout {
+		showBar = showBar && term.IsTerminal(int(os.Stdout.Fd()))
 	}
 
-	log.WithFields(logrus.Fields{"bytes": n}).Info("Downloading file")
-	return nil
-}
+	var r io.Reader = rc
 
-func configureServerCommand(app *kingpin.Application) error {
-	conf, err := newConfig()
-	if err != nil {
-		return err
+	if showBar {
+		bar := pb.New64(size)
+		bar.SetUnits(pb.U_BYTES)
+		bar.Output = os.Stderr
+		if size <= 0 {
+			bar.ShowBar = false
+			bar.ShowPercent = false
+			bar.ShowTimeLeft = false
+		}
+		bar.Start()
+		defer bar.Finish()
+		r = bar.NewProxyReader(rc)
 	}
 
-	s, err := server.New(conf, version())
+	n, err := io.Copy(w, r)
 	if err != nil {
-		return err
+		return fmt.Errorf("Downloading failed: %s", err.Error())
 	}
 
+	if !toStdout {
+		log.WithFields(logrus.Fields{"bytes": n}).Info("Downloading file")
+	}
+	return nil
+}
+
+func configureServerCommand(app *kingpin.Application) error {
+	s := &server.Server{}
+
 	cmd := app.Command("server", "Run the proxy (and web) server")
 	cmd.Flag("port", "The port to listen on").
 		StringVar(&s.Port)
@@ -289,9 +358,37 @@ func configureServerCommand(app *kingpin.Application) error {
 	cmd.Flag("hostname", "The hostname to use for the links back to the server").
 		StringVar(&s.Hostname)
 
+	cmd.Flag("tls-cert", "The path to a TLS certificate to serve HTTPS with").
+		StringVar(&s.TLSCert)
+
+	cmd.Flag("tls-key", "The path to the TLS certificate's private key").
+		StringVar(&s.TLSKey)
+
+	cmd.Flag("tls-bind", "The address to bind the HTTPS listener to").
+		StringVar(&s.TLSBind)
+
+	cmd.Flag("tls-only", "Redirect plain HTTP requests to HTTPS once TLS is enabled").
+		BoolVar(&s.TLSOnly)
+
+	cmd.Flag("acme", "Automatically obtain a TLS certificate from Let's Encrypt for the given hostname(s)").
+		StringsVar(&s.AutoTLSHosts)
+
+	cmd.Flag("shutdown-timeout", "How long to wait for in-flight requests to finish during shutdown").
+		DurationVar(&s.ShutdownTimeout)
+
 	configureGlobalFlags(cmd)
 	cmd.Action(func(c *kingpin.ParseContext) error {
 		applyGlobalFlags()
+
+		conf, err := newConfig()
+		if err != nil {
+			return err
+		}
+
+		if err := s.ApplyConfig(conf, version()); err != nil {
+			return err
+		}
+
 		return serverCommand(s)
 	})
 
@@ -438,9 +535,11 @@ func runServiceCommand(prg *program) error {
 		log.Fatal(err)
 	}
 
-	logger.SetOutput(ioutil.Discard)
+	// logger.Configure (run from newConfig, called by the service's
+	// program) already routed output to the configured file/syslog
+	// sink. Add the platform service logger as an extra destination
+	// rather than replacing it, so `service logs` still works.
 	logger.AddHook(&serviceLogHook{prg.logger})
-	logger.SetFormatter(&serviceLogFormatter{})
 
 	go func() {
 		for {
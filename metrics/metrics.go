@@ -0,0 +1,78 @@
+// Package metrics holds the Prometheus collectors Cardigann exposes on
+// /metrics, plus small helpers for recording against them so that
+// instrumentation stays out of the indexer/torznab/server code paths
+// that actually do the work.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	SearchTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cardigann_search_total",
+		Help: "Total number of torznab searches, by indexer and outcome",
+	}, []string{"indexer", "status"})
+
+	SearchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "cardigann_search_duration_seconds",
+		Help: "Time taken to complete a torznab search, by indexer",
+	}, []string{"indexer"})
+
+	DownloadTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cardigann_download_total",
+		Help: "Total number of torrent downloads, by indexer and outcome",
+	}, []string{"indexer", "status"})
+
+	LoginFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cardigann_login_failures_total",
+		Help: "Total number of failed indexer logins, by indexer",
+	}, []string{"indexer"})
+
+	IndexersEnabled = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cardigann_indexers_enabled",
+		Help: "Number of indexer definitions currently enabled in configuration",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		SearchTotal,
+		SearchDuration,
+		DownloadTotal,
+		LoginFailuresTotal,
+		IndexersEnabled,
+	)
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// ObserveSearch records the outcome and duration of a single search
+// against indexer.
+func ObserveSearch(indexer string, d time.Duration, err error) {
+	SearchTotal.WithLabelValues(indexer, outcome(err)).Inc()
+	SearchDuration.WithLabelValues(indexer).Observe(d.Seconds())
+}
+
+// ObserveDownload records the outcome of a single torrent download
+// against indexer.
+func ObserveDownload(indexer string, err error) {
+	DownloadTotal.WithLabelValues(indexer, outcome(err)).Inc()
+}
+
+// ObserveLoginFailure records a failed login attempt against indexer.
+func ObserveLoginFailure(indexer string) {
+	LoginFailuresTotal.WithLabelValues(indexer).Inc()
+}
+
+// SetIndexersEnabled updates the indexers-enabled gauge to n.
+func SetIndexersEnabled(n int) {
+	IndexersEnabled.Set(float64(n))
+}
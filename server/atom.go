@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/cardigann/cardigann/torznab"
+)
+
+// atomHandler adapts torznabHandler (which renders the Torznab RSS
+// dialect) to Atom 1.0, by re-marshaling its ResultFeed rather than
+// duplicating the search/download handling it already does.
+func atomHandler(torznabHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		torznabHandler.ServeHTTP(rec, r)
+
+		if rec.Code != http.StatusOK {
+			w.WriteHeader(rec.Code)
+			w.Write(rec.Body.Bytes())
+			return
+		}
+
+		var feed torznab.ResultFeed
+		if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+			http.Error(w, "Failed to render atom feed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		atom, err := torznab.MarshalAtom(&feed)
+		if err != nil {
+			http.Error(w, "Failed to render atom feed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+		w.Write(atom)
+	})
+}
+
+// negotiateAtom wraps h so that a request for application/atom+xml gets
+// served the Atom rendering of the same feed instead of the default
+// Torznab RSS, without disturbing any other Accept header.
+func negotiateAtom(h http.Handler) http.Handler {
+	atom := atomHandler(h)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), "application/atom+xml") {
+			atom.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func torznabStub() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <title>example torznab feed</title>
+    <item>
+      <title>Some.Release.2016</title>
+      <guid>abc123</guid>
+      <link>http://example.com/download/abc123</link>
+    </item>
+  </channel>
+</rss>`))
+	})
+}
+
+func TestAtomHandler(t *testing.T) {
+	h := atomHandler(torznabStub())
+
+	req := httptest.NewRequest("GET", "/example", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/atom+xml; charset=utf-8" {
+		t.Errorf("got Content-Type %q, want application/atom+xml", ct)
+	}
+}
+
+func TestNegotiateAtomFallsBackToRSS(t *testing.T) {
+	h := negotiateAtom(torznabStub())
+
+	req := httptest.NewRequest("GET", "/example", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Errorf("got Content-Type %q, want the underlying handler's application/xml", ct)
+	}
+}
+
+func TestNegotiateAtomHonoursAcceptHeader(t *testing.T) {
+	h := negotiateAtom(torznabStub())
+
+	req := httptest.NewRequest("GET", "/example", nil)
+	req.Header.Set("Accept", "application/atom+xml")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/atom+xml; charset=utf-8" {
+		t.Errorf("got Content-Type %q, want application/atom+xml", ct)
+	}
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// indexerStatus reports whether a single indexer definition has
+// finished loading, for the /readyz JSON report.
+type indexerStatus struct {
+	Indexer string `json:"indexer"`
+	Loaded  bool   `json:"loaded"`
+}
+
+// readiness records the load status of every enabled indexer
+// definition. It starts out not-ready; Listen calls setReady with each
+// definition's status once it has tried to load them all.
+type readiness struct {
+	ready  int32
+	status []indexerStatus
+}
+
+func (r *readiness) setReady(status []indexerStatus) {
+	r.status = status
+	atomic.StoreInt32(&r.ready, 1)
+}
+
+func (r *readiness) isReady() bool {
+	return atomic.LoadInt32(&r.ready) == 1
+}
+
+// healthzHandler always reports 200 once the process is serving
+// requests at all.
+func healthzHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// readyzHandler reports 200, and a per-indexer breakdown, once every
+// enabled indexer definition has loaded; 503 until then.
+func readyzHandler(r *readiness) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var status []indexerStatus
+		ready := r.isReady()
+		if ready {
+			status = r.status
+			for _, s := range status {
+				if !s.Loaded {
+					ready = false
+				}
+			}
+		}
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"github.com/cardigann/cardigann/config"
+	"github.com/cardigann/cardigann/indexer"
+	"github.com/cardigann/cardigann/torznab"
+)
+
+// newInstrumentedRunner builds the torznab.Indexer NewHandler uses to
+// serve an inbound search/download/login request for key, the same way
+// indexer.NewRunner would, wrapped in torznab.Instrument so real web/API
+// traffic (not just the equivalent CLI commands) is reflected in the
+// cardigann_search_*/cardigann_download_*/cardigann_login_failures_total
+// metrics.
+func newInstrumentedRunner(key string, opts indexer.RunnerOpts) (torznab.Indexer, error) {
+	if key == "aggregate" {
+		return newInstrumentedAggregate(opts)
+	}
+
+	def, err := indexer.DefaultDefinitionLoader.Load(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return torznab.Instrument(indexer.NewRunner(def, opts), key), nil
+}
+
+func newInstrumentedAggregate(opts indexer.RunnerOpts) (torznab.Indexer, error) {
+	keys, err := indexer.DefaultDefinitionLoader.List()
+	if err != nil {
+		return nil, err
+	}
+
+	agg := indexer.Aggregate{}
+	for _, key := range keys {
+		if config.IsSectionEnabled(key, opts.Config) {
+			def, err := indexer.DefaultDefinitionLoader.Load(key)
+			if err != nil {
+				return nil, err
+			}
+
+			agg = append(agg, torznab.Instrument(indexer.NewRunner(def, opts), key))
+		}
+	}
+
+	return agg, nil
+}
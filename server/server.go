@@ -1,74 +1,142 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/crypto/acme/autocert"
 
 	"github.com/cardigann/cardigann/config"
 	"github.com/cardigann/cardigann/indexer"
 	"github.com/cardigann/cardigann/logger"
+	"github.com/cardigann/cardigann/metrics"
 )
 
+// defaultShutdownTimeout is how long Listen waits for in-flight requests
+// to finish once a shutdown signal is received, unless ShutdownTimeout
+// overrides it.
+const defaultShutdownTimeout = 10 * time.Second
+
+var log = logger.Logger
+
 // Server is an http server which wraps the Handler
 type Server struct {
 	Bind, Port, Passphrase string
 	PathPrefix             string
 	Hostname               string
-	version                string
-	config                 config.Config
+
+	// TLSCert and TLSKey configure a static certificate/key pair to
+	// serve HTTPS with. AutoTLSHosts enables automatic certificates
+	// from Let's Encrypt via autocert for the given hostnames instead.
+	TLSCert, TLSKey, TLSBind string
+	AutoTLSHosts             []string
+
+	// TLSOnly makes the plain HTTP listener, once TLS is enabled,
+	// redirect every request that reaches it to HTTPS instead of
+	// serving it. The plain listener itself keeps running.
+	TLSOnly bool
+
+	// ShutdownTimeout bounds how long Listen waits for in-flight
+	// requests to finish on SIGINT/SIGTERM before forcing the
+	// listeners closed.
+	ShutdownTimeout time.Duration
+
+	version string
+	config  config.Config
 }
 
 func New(conf config.Config, version string) (*Server, error) {
-	bind, err := config.GetGlobalConfig("bind", "0.0.0.0", conf)
-	if err != nil {
+	s := &Server{}
+	if err := s.ApplyConfig(conf, version); err != nil {
 		return nil, err
 	}
+	return s, nil
+}
 
-	port, err := config.GetGlobalConfig("port", "5060", conf)
-	if err != nil {
-		return nil, err
+// ApplyConfig fills in any of Bind/Port/PathPrefix/Passphrase/ShutdownTimeout
+// still at their zero value from conf, and an empty Hostname with
+// "localhost", without clobbering values
+// already set on s (e.g. by command line flags parsed after s was
+// constructed for flag binding, which should win over the config file).
+func (s *Server) ApplyConfig(conf config.Config, version string) error {
+	if s.Bind == "" {
+		bind, err := config.GetGlobalConfig("bind", "0.0.0.0", conf)
+		if err != nil {
+			return err
+		}
+		s.Bind = bind
 	}
 
-	prefix, err := config.GetGlobalConfig("pathprefix", "", conf)
-	if err != nil {
-		return nil, err
+	if s.Port == "" {
+		port, err := config.GetGlobalConfig("port", "5060", conf)
+		if err != nil {
+			return err
+		}
+		s.Port = port
 	}
 
-	passphrase, err := config.GetGlobalConfig("passphrase", "", conf)
-	if err != nil {
-		return nil, err
+	if s.PathPrefix == "" {
+		prefix, err := config.GetGlobalConfig("pathprefix", "", conf)
+		if err != nil {
+			return err
+		}
+		s.PathPrefix = prefix
+	}
+
+	if s.Passphrase == "" {
+		passphrase, err := config.GetGlobalConfig("passphrase", "", conf)
+		if err != nil {
+			return err
+		}
+		s.Passphrase = passphrase
+	}
+
+	if s.Hostname == "" {
+		s.Hostname = "localhost"
+	}
+
+	if s.ShutdownTimeout == 0 {
+		timeout, err := config.GetGlobalConfig("shutdowntimeout", defaultShutdownTimeout.String(), conf)
+		if err != nil {
+			return err
+		}
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return fmt.Errorf("Invalid shutdowntimeout %q: %s", timeout, err.Error())
+		}
+		s.ShutdownTimeout = d
 	}
 
 	if version == "" {
 		version = "dev"
 	}
 
-	return &Server{
-		Hostname:   "localhost",
-		Bind:       bind,
-		Port:       port,
-		Passphrase: passphrase,
-		PathPrefix: prefix,
-		config:     conf,
-		version:    version,
-	}, nil
+	s.config = conf
+	s.version = version
+	return nil
 }
 
 func (s *Server) Listen() error {
-	logger.Logger.Infof("Cardigann %s", s.version)
+	log.Infof("Cardigann %s", s.version)
 
 	path, err := config.GetConfigPath()
 	if err != nil {
 		return err
 	}
 
-	logger.Logger.Infof("Reading config from %s", path)
-	logger.Logger.Debugf("Cache dir is %s", config.GetCachePath("/"))
+	log.Infof("Reading config from %s", path)
+	log.Debugf("Cache dir is %s", config.GetCachePath("/"))
 
 	for _, dir := range config.GetDefinitionDirs() {
 		if _, err := os.Stat(dir); os.IsExist(err) {
-			logger.Logger.Infof("Loading definitions from %s", dir)
+			log.Infof("Loading definitions from %s", dir)
 		}
 	}
 
@@ -77,7 +145,7 @@ func (s *Server) Listen() error {
 		return err
 	}
 
-	logger.Logger.Debugf("Found %d built-in definitions", len(builtins))
+	log.Debugf("Found %d built-in definitions", len(builtins))
 
 	defs, err := indexer.DefaultDefinitionLoader.List()
 	if err != nil {
@@ -85,16 +153,40 @@ func (s *Server) Listen() error {
 	}
 
 	active := 0
+	enabledKeys := []string{}
 	for _, key := range defs {
 		if config.IsSectionEnabled(key, s.config) {
 			active++
+			enabledKeys = append(enabledKeys, key)
 		}
 	}
 
-	logger.Logger.Infof("Found %d indexers enabled in configuration", active)
+	log.Infof("Found %d indexers enabled in configuration", active)
+	metrics.SetIndexersEnabled(active)
+
+	ready := &readiness{}
+
+	// Actually load each enabled definition before reporting ready, so
+	// /readyz reflects real parse/load failures rather than just which
+	// sections are enabled. This runs in the background so it doesn't
+	// delay the listeners coming up.
+	go func() {
+		statuses := make([]indexerStatus, 0, len(enabledKeys))
+		for _, key := range enabledKeys {
+			ilog := log.WithField("indexer", key)
+			_, err := indexer.DefaultDefinitionLoader.Load(key)
+			if err != nil {
+				ilog.WithError(err).Warn("Failed to load indexer definition")
+			} else {
+				ilog.Debug("Indexer definition loaded")
+			}
+			statuses = append(statuses, indexerStatus{Indexer: key, Loaded: err == nil})
+		}
+		ready.setReady(statuses)
+	}()
 
 	listenOn := fmt.Sprintf("%s:%s", s.Bind, s.Port)
-	logger.Logger.Infof("Listening on %s", listenOn)
+	log.Infof("Listening on %s", listenOn)
 
 	h, err := NewHandler(Params{
 		BaseURL:    fmt.Sprintf("http://%s:%s%s", s.Hostname, s.Port, s.PathPrefix),
@@ -102,10 +194,104 @@ func (s *Server) Listen() error {
 		PathPrefix: s.PathPrefix,
 		Config:     s.config,
 		Version:    s.version,
+		NewRunner:  newInstrumentedRunner,
 	})
 	if err != nil {
 		return err
 	}
 
-	return http.ListenAndServe(listenOn, h)
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", healthzHandler())
+	mux.Handle("/readyz", readyzHandler(ready))
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/atom/", http.StripPrefix("/atom", atomHandler(h)))
+	mux.Handle("/", negotiateAtom(h))
+
+	tlsEnabled := (s.TLSCert != "" && s.TLSKey != "") || len(s.AutoTLSHosts) > 0
+
+	var httpSrv, httpsSrv *http.Server
+	httpSrv = &http.Server{Addr: listenOn, Handler: mux}
+
+	if tlsEnabled {
+		tlsBind := s.TLSBind
+		if tlsBind == "" {
+			tlsBind = fmt.Sprintf("%s:5443", s.Bind)
+		}
+
+		tlsCfg := hardenedTLSConfig()
+		var manager *autocert.Manager
+
+		if len(s.AutoTLSHosts) > 0 {
+			manager = newAutocertManager(s.AutoTLSHosts)
+			tlsCfg.GetCertificate = manager.GetCertificate
+			log.Infof("Requesting automatic TLS certificates for %v", s.AutoTLSHosts)
+		} else {
+			log.Infof("Loading TLS certificate from %s", s.TLSCert)
+		}
+
+		httpsSrv = &http.Server{
+			Addr:      tlsBind,
+			Handler:   hstsHandler(mux),
+			TLSConfig: tlsCfg,
+		}
+
+		if s.TLSOnly {
+			_, httpsPort, err := net.SplitHostPort(tlsBind)
+			if err != nil {
+				return err
+			}
+			httpSrv.Handler = redirectHandler(httpsPort)
+		}
+		if manager != nil {
+			httpSrv.Handler = manager.HTTPHandler(httpSrv.Handler)
+		}
+
+		log.Infof("Listening on %s (https)", tlsBind)
+	}
+
+	errs := make(chan error, 2)
+
+	go func() {
+		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errs <- err
+		}
+	}()
+
+	if httpsSrv != nil {
+		go func() {
+			var err error
+			if len(s.AutoTLSHosts) > 0 {
+				err = httpsSrv.ListenAndServeTLS("", "")
+			} else {
+				err = httpsSrv.ListenAndServeTLS(s.TLSCert, s.TLSKey)
+			}
+			if err != nil && err != http.ErrServerClosed {
+				errs <- err
+			}
+		}()
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-sig:
+		log.Info("Shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpSrv.Shutdown(ctx); err != nil {
+		return err
+	}
+	if httpsSrv != nil {
+		if err := httpsSrv.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
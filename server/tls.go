@@ -0,0 +1,66 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"path/filepath"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/cardigann/cardigann/config"
+)
+
+// newAutocertManager returns an autocert.Manager which fetches and caches
+// certificates for hosts under the configured cache directory.
+func newAutocertManager(hosts []string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(filepath.Clean(config.GetCachePath("acme"))),
+	}
+}
+
+// hardenedTLSConfig returns a tls.Config with the minimum version and
+// cipher suites we're prepared to recommend operators expose to the
+// internet.
+func hardenedTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		},
+		PreferServerCipherSuites: true,
+	}
+}
+
+// hstsHandler wraps h, adding a Strict-Transport-Security header to every
+// response so browsers remember to use HTTPS for this host.
+func hstsHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=15768000; includeSubDomains")
+		h.ServeHTTP(w, r)
+	})
+}
+
+// redirectHandler sends every request to the same host over HTTPS,
+// listening on httpsPort (as taken from the https listener's bind
+// address) rather than whatever port the plain HTTP request arrived on.
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,97 @@
+package torznab
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// torznabAttrNS is the namespace torznab-specific attributes ride along
+// in on each Atom entry, mirroring the attr elements used in the RSS
+// dialect.
+const torznabAttrNS = "http://torznab.com/schemas/2015/feed"
+
+type atomFeed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+
+	// XMLNSTorznab declares the torznab: prefix used on each entry's
+	// <torznab:attr> elements below, once on the root, the same way the
+	// RSS dialect declares it on its root <rss> element.
+	XMLNSTorznab string `xml:"xmlns:torznab,attr"`
+
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type torznabAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type atomEntry struct {
+	Title   string        `xml:"title"`
+	ID      string        `xml:"id"`
+	Updated string        `xml:"updated"`
+	Author  atomAuthor    `xml:"author"`
+	Summary string        `xml:"summary,omitempty"`
+	Links   []atomLink    `xml:"link"`
+	Attrs   []torznabAttr `xml:"torznab:attr"`
+}
+
+// atomTagURI builds a stable tag: URI (RFC 4151) for site/id, using year
+// to scope it the way a real domain-backed feed would use a path.
+func atomTagURI(site string, year int, id string) string {
+	return fmt.Sprintf("tag:%s,%d:%s", site, year, id)
+}
+
+// MarshalAtom renders feed as an Atom 1.0 document, the way
+// xml.Marshal(feed) renders the Torznab RSS dialect.
+func MarshalAtom(feed *ResultFeed) ([]byte, error) {
+	now := time.Now().UTC()
+
+	af := atomFeed{
+		XMLNSTorznab: torznabAttrNS,
+		Title:        fmt.Sprintf("%s torznab feed", feed.Site),
+		ID:           atomTagURI(feed.Site, now.Year(), "feed"),
+		Updated:      now.Format(time.RFC3339),
+	}
+
+	for _, r := range feed.Results {
+		updated := r.PublishDate
+		if updated.IsZero() {
+			updated = now
+		}
+
+		af.Entries = append(af.Entries, atomEntry{
+			Title:   r.Title,
+			ID:      atomTagURI(feed.Site, updated.Year(), r.GUID),
+			Updated: updated.UTC().Format(time.RFC3339),
+			Author:  atomAuthor{Name: feed.Site},
+			Summary: r.Description,
+			Links: []atomLink{
+				{Rel: "alternate", Href: r.Link},
+			},
+			Attrs: []torznabAttr{
+				{Name: "size", Value: fmt.Sprintf("%d", r.Size)},
+				{Name: "seeders", Value: fmt.Sprintf("%d", r.Seeders)},
+				{Name: "peers", Value: fmt.Sprintf("%d", r.Peers)},
+				{Name: "category", Value: fmt.Sprintf("%d", r.Category)},
+				{Name: "infohash", Value: r.InfoHash},
+			},
+		})
+	}
+
+	return xml.MarshalIndent(af, "", "  ")
+}
@@ -0,0 +1,98 @@
+package torznab
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAtomTagURI(t *testing.T) {
+	got := atomTagURI("example", 2016, "abc123")
+	want := "tag:example,2016:abc123"
+
+	if got != want {
+		t.Errorf("atomTagURI() = %q, want %q", got, want)
+	}
+}
+
+func TestMarshalAtom(t *testing.T) {
+	publishDate := time.Date(2016, time.March, 4, 12, 0, 0, 0, time.UTC)
+
+	feed := &ResultFeed{
+		Site: "example",
+		Results: []ReleaseInfo{
+			{
+				Title:       "Some.Release.2016",
+				GUID:        "abc123",
+				Link:        "http://example.com/download/abc123",
+				Description: "A release",
+				PublishDate: publishDate,
+				Size:        1234,
+				Seeders:     5,
+				Peers:       10,
+				Category:    5000,
+				InfoHash:    "deadbeef",
+			},
+		},
+	}
+
+	out, err := MarshalAtom(feed)
+	if err != nil {
+		t.Fatalf("MarshalAtom() error = %v", err)
+	}
+
+	doc := string(out)
+
+	if !strings.Contains(doc, `xmlns:torznab="`+torznabAttrNS+`"`) {
+		t.Errorf("MarshalAtom() output missing xmlns:torznab declaration: %s", doc)
+	}
+
+	if !strings.Contains(doc, "<torznab:attr") {
+		t.Errorf("MarshalAtom() output missing torznab:attr elements: %s", doc)
+	}
+
+	if strings.Contains(doc, `xmlns="`+torznabAttrNS+`"`) {
+		t.Errorf("MarshalAtom() redeclared the default namespace instead of using the torznab: prefix: %s", doc)
+	}
+
+	if !strings.Contains(doc, "tag:example,2016:abc123") {
+		t.Errorf("MarshalAtom() output missing expected entry id: %s", doc)
+	}
+
+	if !strings.Contains(doc, "2016-03-04T12:00:00Z") {
+		t.Errorf("MarshalAtom() output missing RFC3339 updated date: %s", doc)
+	}
+
+	var af atomFeed
+	if err := xml.Unmarshal(out, &af); err != nil {
+		t.Fatalf("failed to unmarshal MarshalAtom() output: %v", err)
+	}
+
+	if len(af.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(af.Entries))
+	}
+}
+
+func TestMarshalAtomZeroPublishDate(t *testing.T) {
+	feed := &ResultFeed{
+		Site: "example",
+		Results: []ReleaseInfo{
+			{Title: "No date", GUID: "nodate", Link: "http://example.com/download/nodate"},
+		},
+	}
+
+	out, err := MarshalAtom(feed)
+	if err != nil {
+		t.Fatalf("MarshalAtom() error = %v", err)
+	}
+
+	var af atomFeed
+	if err := xml.Unmarshal(out, &af); err != nil {
+		t.Fatalf("failed to unmarshal MarshalAtom() output: %v", err)
+	}
+
+	if len(af.Entries) != 1 || af.Entries[0].Updated == "" {
+		t.Errorf("expected a zero PublishDate to fall back to now, got entries: %+v", af.Entries)
+	}
+}
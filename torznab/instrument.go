@@ -0,0 +1,45 @@
+package torznab
+
+import (
+	"io"
+	"time"
+
+	"github.com/cardigann/cardigann/metrics"
+)
+
+// instrumentedIndexer wraps an Indexer, recording Prometheus metrics for
+// every Search/Download/Login call it handles, so indexer
+// implementations don't need to know about metrics at all.
+type instrumentedIndexer struct {
+	Indexer
+	name string
+}
+
+// Instrument wraps ind so that every Search/Download/Login call is
+// recorded against name (typically the indexer's site key) in the
+// cardigann_search_*/cardigann_download_*/cardigann_login_failures_total
+// metrics.
+func Instrument(ind Indexer, name string) Indexer {
+	return &instrumentedIndexer{Indexer: ind, name: name}
+}
+
+func (i *instrumentedIndexer) Search(query Query) (ResultFeed, error) {
+	start := time.Now()
+	feed, err := i.Indexer.Search(query)
+	metrics.ObserveSearch(i.name, time.Since(start), err)
+	return feed, err
+}
+
+func (i *instrumentedIndexer) Download(u string) (io.ReadCloser, int64, error) {
+	rc, size, err := i.Indexer.Download(u)
+	metrics.ObserveDownload(i.name, err)
+	return rc, size, err
+}
+
+func (i *instrumentedIndexer) Login() error {
+	err := i.Indexer.Login()
+	if err != nil {
+		metrics.ObserveLoginFailure(i.name)
+	}
+	return err
+}